@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// GitRepository wraps a cloned repository together with its local worktree
+// path, adding helpers update strategies need to read state from branches
+// other than the one currently checked out.
+type GitRepository struct {
+	*git.Repository
+	LocalPath string
+}
+
+// WithBranch returns the tree of branchName (looked up as a local branch
+// first, then as an origin remote-tracking branch) without touching the
+// current worktree checkout. This lets an update strategy read a file from,
+// say, a "staging" branch while switchBranch has "main" checked out for the
+// PR it's building - the cross-branch promotion case multi-branch fetch
+// exists for.
+func (r *GitRepository) WithBranch(branchName string) (*object.Tree, error) {
+	ref, err := resolveBranchReference(r.Repository, branchName)
+	if err != nil {
+		return nil, err
+	}
+	commit, err := r.Repository.CommitObject(ref.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load commit for branch %s: %w", branchName, err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tree for branch %s: %w", branchName, err)
+	}
+	return tree, nil
+}
+
+// resolveBranchReference looks up branchName as a local branch, falling
+// back to origin's remote-tracking branch (populated when GitHubOptions.
+// FetchAllRefs is set).
+func resolveBranchReference(gitRepo *git.Repository, branchName string) (*plumbing.Reference, error) {
+	candidates := []plumbing.ReferenceName{
+		plumbing.NewBranchReferenceName(branchName),
+		plumbing.NewRemoteReferenceName("origin", branchName),
+	}
+	for _, refName := range candidates {
+		ref, err := gitRepo.Reference(refName, true)
+		if err == nil {
+			return ref, nil
+		}
+	}
+	return nil, fmt.Errorf("branch %s not found locally or on origin", branchName)
+}
+
+// BranchReader is implemented by update strategies that read state from
+// branches other than the one being updated, e.g. to promote a file from a
+// "staging" branch into a "main" PR. cloneGitRepository fetches every branch
+// SourceBranches names (in addition to the one being checked out) so
+// WithBranch can resolve them, unless GitHubOptions.FetchAllRefs is already
+// fetching everything.
+type BranchReader interface {
+	SourceBranches() []string
+}
+
+// branchReaderSourceBranches returns strategy.SourceBranches() if strategy
+// implements BranchReader, or nil otherwise. strategy is typed any because
+// it's whatever update-strategy value the caller is running, most of which
+// have no need to read other branches and so don't implement BranchReader.
+func branchReaderSourceBranches(strategy any) []string {
+	reader, ok := strategy.(BranchReader)
+	if !ok {
+		return nil
+	}
+	return reader.SourceBranches()
+}
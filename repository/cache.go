@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/gofrs/flock"
+	"github.com/sirupsen/logrus"
+)
+
+// mirrorRefSpec fetches every branch from origin into the mirror's own
+// refs/heads/*, the same layout `git clone --mirror` produces.
+const mirrorRefSpec = config.RefSpec("+refs/heads/*:refs/heads/*")
+
+// mirrorPath returns where repo's bare mirror lives under cacheDir.
+func mirrorPath(cacheDir string, repo Repository) string {
+	return filepath.Join(cacheDir, repo.Owner, repo.Name+".git")
+}
+
+// cloneFromCache satisfies a clone of repo's referenceName at localPath by
+// updating (or creating) a bare mirror under cacheDir and then cloning
+// localPath from that local mirror instead of the remote, so repeated runs
+// over the same repository only ever fetch new commits once. It falls back
+// to a fresh mirror if the existing one is corrupt.
+func cloneFromCache(ctx context.Context, cacheDir string, repo Repository, url string, referenceName plumbing.ReferenceName, auth transport.AuthMethod, localPath string) (*git.Repository, error) {
+	path := mirrorPath(cacheDir, repo)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache dir for %s: %w", repo.FullName(), err)
+	}
+
+	lock := flock.New(path + ".lock")
+	if err := lock.Lock(); err != nil {
+		return nil, fmt.Errorf("failed to lock mirror cache for %s: %w", repo.FullName(), err)
+	}
+	defer lock.Unlock()
+
+	if err := updateMirror(ctx, path, url, auth); err != nil {
+		logrus.WithError(err).WithField("repository-name", repo.FullName()).Warn("Git mirror cache looked corrupt, re-cloning it")
+		if rmErr := os.RemoveAll(path); rmErr != nil {
+			return nil, fmt.Errorf("failed to remove corrupt mirror cache for %s: %w", repo.FullName(), rmErr)
+		}
+		if err := updateMirror(ctx, path, url, auth); err != nil {
+			return nil, fmt.Errorf("failed to create mirror cache for %s: %w", repo.FullName(), err)
+		}
+	}
+
+	gitRepo, err := git.PlainCloneContext(ctx, localPath, false, &git.CloneOptions{
+		URL:           path,
+		ReferenceName: referenceName,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to clone %s from local mirror %s: %w", repo.FullName(), path, err)
+	}
+
+	// The clone above points "origin" at the local mirror path, so it's
+	// fast to clone from; but anything that fetches or pushes afterwards
+	// (fetchAndRebase, FetchAllRefs, pushChanges) needs "origin" to mean the
+	// real host, not the cache directory.
+	if err := resetOriginRemote(gitRepo, url); err != nil {
+		return nil, fmt.Errorf("failed to point %s's origin at %s: %w", repo.FullName(), url, err)
+	}
+
+	return gitRepo, nil
+}
+
+// resetOriginRemote repoints gitRepo's "origin" remote at remoteURL,
+// replacing whatever it was cloned from.
+func resetOriginRemote(gitRepo *git.Repository, remoteURL string) error {
+	if err := gitRepo.DeleteRemote("origin"); err != nil {
+		return fmt.Errorf("failed to remove origin remote: %w", err)
+	}
+	if _, err := gitRepo.CreateRemote(&config.RemoteConfig{
+		Name: "origin",
+		URLs: []string{remoteURL},
+	}); err != nil {
+		return fmt.Errorf("failed to create origin remote for %s: %w", remoteURL, err)
+	}
+	return nil
+}
+
+// updateMirror clones path as a bare repository if it doesn't exist yet,
+// then fetches every branch into it with prune using mirrorRefSpec.
+func updateMirror(ctx context.Context, path, url string, auth transport.AuthMethod) error {
+	mirror, err := git.PlainOpen(path)
+	if err != nil {
+		mirror, err = git.PlainCloneContext(ctx, path, true, &git.CloneOptions{
+			URL:  url,
+			Auth: auth,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to clone mirror from %s: %w", url, err)
+		}
+	}
+
+	err = mirror.FetchContext(ctx, &git.FetchOptions{
+		RefSpecs: []config.RefSpec{mirrorRefSpec},
+		Auth:     auth,
+		Prune:    true,
+		Force:    true,
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return fmt.Errorf("failed to fetch mirror at %s: %w", path, err)
+	}
+	return nil
+}
@@ -2,7 +2,9 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
 	"time"
@@ -11,12 +13,21 @@ import (
 	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
-	"github.com/go-git/go-git/v5/plumbing/transport/http"
 	"github.com/sirupsen/logrus"
 )
 
-func cloneGitRepository(ctx context.Context, repo Repository, localPath string, options GitHubOptions) (*git.Repository, error) {
-	url := fmt.Sprintf("https://github.worldpay.com/%s.git", repo.FullName())
+// cloneGitRepository clones repo into localPath. If strategy implements
+// BranchReader, its declared SourceBranches are fetched into
+// refs/remotes/origin/* alongside the checked-out branch, so the update
+// strategy can read them via GitRepository.WithBranch; this is skipped when
+// options.FetchAllRefs already fetches every branch.
+func cloneGitRepository(ctx context.Context, repo Repository, localPath string, options GitHubOptions, strategy any) (*GitRepository, error) {
+	gitConfig := gitConfigForRepository(repo, options)
+	provider, err := NewGitProvider(gitConfig.Provider)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select git provider for %s: %w", repo.FullName(), err)
+	}
+	url := provider.CloneURL(repo, gitConfig)
 
 	branch := "HEAD"
 	if b, ok := repo.Params["branch"]; ok && strings.TrimSpace(b) != "" {
@@ -24,35 +35,76 @@ func cloneGitRepository(ctx context.Context, repo Repository, localPath string,
 	}
 	referenceName := plumbing.ReferenceName(branch)
 	logrus.WithFields(logrus.Fields{
+		"git-provider":  provider.Name(),
 		"git-url":       url,
 		"git-reference": referenceName.String(),
 		"local-path":    localPath,
 	}).Trace("Cloning git repository")
 
-	_, token, err := githubClient(ctx, options)
+	auth, err := provider.Auth(gitConfig)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create github client: %w", err)
+		return nil, fmt.Errorf("failed to build auth for %s: %w", repo.FullName(), err)
 	}
 
-	gitRepo, err := git.PlainCloneContext(ctx, localPath, false, &git.CloneOptions{
-		ReferenceName: referenceName,
-		URL:           url,
-		Auth: &http.BasicAuth{
-			Username: "x-access-token", // For GitHub Apps, the username must be `x-access-token`. For Personal Tokens, it doesn't matter.
-			Password: token,
-		},
+	var gitRepo *git.Repository
+	err = withRetry(ctx, options.Retry, func(attempt int) error {
+		if attempt > 1 {
+			// A previous attempt may have left a partial checkout behind;
+			// PlainCloneContext refuses to clone into a non-empty dir.
+			if err := os.RemoveAll(localPath); err != nil {
+				return fmt.Errorf("failed to clean up %s before retrying clone: %w", localPath, err)
+			}
+		}
+		var cloneErr error
+		if options.CacheDir != "" {
+			gitRepo, cloneErr = cloneFromCache(ctx, options.CacheDir, repo, url, referenceName, auth, localPath)
+			return cloneErr
+		}
+		gitRepo, cloneErr = git.PlainCloneContext(ctx, localPath, false, &git.CloneOptions{
+			ReferenceName: referenceName,
+			URL:           url,
+			Auth:          auth,
+		})
+		return cloneErr
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to clone git repository from %s to %s: %w", url, localPath, err)
 	}
 
+	if err := fetchLFSObjects(ctx, localPath, url, auth, options.LFS); err != nil {
+		return nil, fmt.Errorf("failed to fetch lfs objects for %s: %w", repo.FullName(), err)
+	}
+
+	switch sourceBranches := branchReaderSourceBranches(strategy); {
+	case options.FetchAllRefs:
+		err = gitRepo.FetchContext(ctx, &git.FetchOptions{
+			RefSpecs: []config.RefSpec{"+refs/heads/*:refs/remotes/origin/*"},
+			Auth:     auth,
+		})
+		if err != nil && err != git.NoErrAlreadyUpToDate {
+			return nil, fmt.Errorf("failed to fetch all refs for %s: %w", repo.FullName(), err)
+		}
+	case len(sourceBranches) > 0:
+		refSpecs := make([]config.RefSpec, len(sourceBranches))
+		for i, branch := range sourceBranches {
+			refSpecs[i] = config.RefSpec(fmt.Sprintf("+refs/heads/%[1]s:refs/remotes/origin/%[1]s", branch))
+		}
+		err = gitRepo.FetchContext(ctx, &git.FetchOptions{
+			RefSpecs: refSpecs,
+			Auth:     auth,
+		})
+		if err != nil && err != git.NoErrAlreadyUpToDate {
+			return nil, fmt.Errorf("failed to fetch source branches %v for %s: %w", sourceBranches, repo.FullName(), err)
+		}
+	}
+
 	logrus.WithFields(logrus.Fields{
 		"git-url":       url,
 		"git-reference": referenceName.String(),
 		"local-path":    localPath,
 	}).Debug("Git repository cloned")
 
-	return gitRepo, nil
+	return &GitRepository{Repository: gitRepo, LocalPath: localPath}, nil
 }
 
 type switchBranchOptions struct {
@@ -122,6 +174,10 @@ func commitChanges(_ context.Context, gitRepo *git.Repository, options UpdateOpt
 		"status":          status.String(),
 	}).Debug("Git status")
 
+	if err := cleanLFSFiles(rootPath, options.LFS); err != nil {
+		return false, fmt.Errorf("failed to clean lfs files in %s: %w", repoName, err)
+	}
+
 	for _, pattern := range options.Git.StagePatterns {
 		err = workTree.AddGlob(pattern)
 		if err != nil {
@@ -141,42 +197,72 @@ func commitChanges(_ context.Context, gitRepo *git.Repository, options UpdateOpt
 		commitMsg.WriteString(options.Git.CommitFooter)
 	}
 
-	commit, err := workTree.Commit(commitMsg.String(),
-		&git.CommitOptions{
-			All: options.Git.StageAllChanged,
-			Author: &object.Signature{
-				Name:  options.Git.AuthorName,
-				Email: options.Git.AuthorEmail,
-				When:  now,
-			},
-			Committer: &object.Signature{
-				Name:  options.Git.CommitterName,
-				Email: options.Git.CommitterEmail,
-				When:  now,
-			},
+	commitOpts := &git.CommitOptions{
+		All: options.Git.StageAllChanged,
+		Author: &object.Signature{
+			Name:  options.Git.AuthorName,
+			Email: options.Git.AuthorEmail,
+			When:  now,
 		},
-	)
+		Committer: &object.Signature{
+			Name:  options.Git.CommitterName,
+			Email: options.Git.CommitterEmail,
+			When:  now,
+		},
+	}
+	if options.Git.SigningMode == "gpg" {
+		signKey, err := loadGPGSignKey(options.Git)
+		if err != nil {
+			return false, fmt.Errorf("failed to load gpg signing key: %w", err)
+		}
+		commitOpts.SignKey = signKey
+	}
+
+	commitHash, err := workTree.Commit(commitMsg.String(), commitOpts)
 	if err != nil {
 		return false, fmt.Errorf("failed to commit: %w", err)
 	}
+
+	if options.Git.SigningMode == "ssh" {
+		commitHash, err = signCommitWithSSH(gitRepo, commitHash, options.Git)
+		if err != nil {
+			return false, fmt.Errorf("failed to sign commit with ssh key: %w", err)
+		}
+		head, err := gitRepo.Head()
+		if err != nil {
+			return false, fmt.Errorf("failed to read HEAD after signing commit: %w", err)
+		}
+		if err := gitRepo.Storer.SetReference(plumbing.NewHashReference(head.Name(), commitHash)); err != nil {
+			return false, fmt.Errorf("failed to update %s to signed commit %s: %w", head.Name(), commitHash, err)
+		}
+	}
+
 	logrus.WithFields(logrus.Fields{
 		"repository-name": repoName,
-		"commit":          commit.String(),
+		"commit":          commitHash.String(),
 	}).Debug("Git commit")
 
 	return true, nil
 }
 
 type pushOptions struct {
+	Repo       Repository
 	GitHubOpts GitHubOptions
 	BranchName string
 	ForcePush  bool
+
+	// PullRequest, when set, opens a pull/merge request for BranchName via
+	// the resolved GitProvider once the push succeeds.
+	PullRequest *PullRequestOptions
 }
 
-func pushChanges(ctx context.Context, gitRepo *git.Repository, opts pushOptions) error {
+// pushChanges pushes the branch in opts to its remote and, if opts.
+// PullRequest is set, opens a pull/merge request for it, returning that
+// request's URL.
+func pushChanges(ctx context.Context, gitRepo *git.Repository, opts pushOptions) (string, error) {
 	workTree, err := gitRepo.Worktree()
 	if err != nil {
-		return fmt.Errorf("failed to open worktree: %w", err)
+		return "", fmt.Errorf("failed to open worktree: %w", err)
 	}
 
 	rootPath := workTree.Filesystem.Root()
@@ -189,32 +275,62 @@ func pushChanges(ctx context.Context, gitRepo *git.Repository, opts pushOptions)
 		refSpec = fmt.Sprintf("+%s", refSpec)
 	}
 
-	_, token, err := githubClient(ctx, opts.GitHubOpts)
+	gitConfig := gitConfigForRepository(opts.Repo, opts.GitHubOpts)
+	provider, err := NewGitProvider(gitConfig.Provider)
 	if err != nil {
-		return fmt.Errorf("failed to create github client: %w", err)
+		return "", fmt.Errorf("failed to select git provider for %s: %w", repoName, err)
+	}
+	auth, err := provider.Auth(gitConfig)
+	if err != nil {
+		return "", fmt.Errorf("failed to build auth for %s: %w", repoName, err)
+	}
+	cloneURL := provider.CloneURL(opts.Repo, gitConfig)
+
+	if err := pushLFSObjects(ctx, rootPath, cloneURL, opts.BranchName, auth, opts.GitHubOpts.LFS); err != nil {
+		return "", fmt.Errorf("failed to push lfs objects for %s: %w", repoName, err)
 	}
 
 	logrus.WithFields(logrus.Fields{
 		"repository-name": repoName,
+		"git-provider":    provider.Name(),
 		"branch":          opts.BranchName,
 		"force":           opts.ForcePush,
 	}).Trace("Pushing git changes")
-	err = gitRepo.PushContext(ctx, &git.PushOptions{
-		RefSpecs: []config.RefSpec{
-			config.RefSpec(refSpec),
-		},
-		Auth: &http.BasicAuth{
-			Username: "x-access-token", // For GitHub Apps, the username must be `x-access-token`. For Personal Tokens, it doesn't matter.
-			Password: token,
-		},
+	err = withRetry(ctx, opts.GitHubOpts.Retry, func(attempt int) error {
+		pushErr := gitRepo.PushContext(ctx, &git.PushOptions{
+			RefSpecs: []config.RefSpec{
+				config.RefSpec(refSpec),
+			},
+			Auth: auth,
+		})
+		if errors.Is(pushErr, git.ErrNonFastForwardUpdate) && !opts.ForcePush {
+			if rebaseErr := fetchAndRebase(ctx, gitRepo, opts.BranchName, auth); rebaseErr != nil {
+				return fmt.Errorf("failed to rebase %s before retrying push: %w", opts.BranchName, rebaseErr)
+			}
+		}
+		return pushErr
 	})
 	if err != nil {
-		return fmt.Errorf("failed to push branch %s to %s: %w", opts.BranchName, repoName, err)
+		return "", fmt.Errorf("failed to push branch %s to %s: %w", opts.BranchName, repoName, err)
 	}
 
 	logrus.WithFields(logrus.Fields{
 		"repository-name": repoName,
 		"branch":          opts.BranchName,
 	}).Debug("Git changes pushed")
-	return nil
+
+	if opts.PullRequest == nil {
+		return "", nil
+	}
+
+	prURL, err := provider.CreatePullRequest(ctx, opts.Repo, gitConfig, *opts.PullRequest)
+	if err != nil {
+		return "", fmt.Errorf("failed to create pull request for %s: %w", repoName, err)
+	}
+	logrus.WithFields(logrus.Fields{
+		"repository-name":  repoName,
+		"git-provider":     provider.Name(),
+		"pull-request-url": prURL,
+	}).Debug("Pull request created")
+	return prURL, nil
 }
@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/google/go-github/v53/github"
+	"golang.org/x/oauth2"
+)
+
+// githubClient builds an authenticated GitHub API client for host (the API
+// base URL is only overridden when enterprise is set, since GitHub.com and
+// GitHub Enterprise use different API roots). Used by githubProvider to
+// create pull requests.
+func githubClient(ctx context.Context, host, token string, enterprise bool) (*github.Client, error) {
+	var httpClient *http.Client
+	if token != "" {
+		httpClient = oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token}))
+	}
+
+	if !enterprise {
+		return github.NewClient(httpClient), nil
+	}
+
+	baseURL := fmt.Sprintf("https://%s/api/v3/", host)
+	client, err := github.NewEnterpriseClient(baseURL, baseURL, httpClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build github enterprise client for %s: %w", host, err)
+	}
+	return client, nil
+}
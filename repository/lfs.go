@@ -0,0 +1,476 @@
+package repository
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	gogithttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// lfsPointerPreamble is the first line of every Git LFS pointer file.
+const lfsPointerPreamble = "version https://git-lfs.github.com/spec/v1"
+
+// lfsPointer is the parsed content of a Git LFS pointer file.
+type lfsPointer struct {
+	OID  string
+	Size int64
+}
+
+// hasGitLFSBinaries reports whether both `git` and `git-lfs` are available
+// on PATH, making the shell-out path usable.
+func hasGitLFSBinaries() bool {
+	if _, err := exec.LookPath("git"); err != nil {
+		return false
+	}
+	if _, err := exec.LookPath("git-lfs"); err != nil {
+		return false
+	}
+	return true
+}
+
+// fetchLFSObjects resolves Git LFS pointer files checked out at localPath
+// into their real content. It shells out to `git lfs pull` when the
+// binaries are available and PreferShellOut is set, and otherwise resolves
+// pointers itself via the LFS Batch API, authenticating with auth (the same
+// credentials used for the git clone/push itself).
+func fetchLFSObjects(ctx context.Context, localPath, remoteURL string, auth transport.AuthMethod, options LFSOptions) error {
+	if !options.Enabled {
+		return nil
+	}
+
+	if options.PreferShellOut && hasGitLFSBinaries() {
+		cmd := exec.CommandContext(ctx, "git", "lfs", "pull")
+		cmd.Dir = localPath
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("git lfs pull failed in %s: %w: %s", localPath, err, out)
+		}
+		return nil
+	}
+
+	pointers, err := findLFSPointerFiles(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to find lfs pointer files in %s: %w", localPath, err)
+	}
+	if len(pointers) == 0 {
+		return nil
+	}
+
+	actions, err := lfsBatch(ctx, remoteURL, "download", pointers, auth)
+	if err != nil {
+		return fmt.Errorf("failed to request lfs batch download from %s: %w", remoteURL, err)
+	}
+	for path, pointer := range pointers {
+		action, ok := actions[pointer.OID]
+		if !ok {
+			return fmt.Errorf("no download action returned for lfs object %s (%s)", pointer.OID, path)
+		}
+		if err := downloadLFSObject(ctx, localPath, path, pointer, action, auth); err != nil {
+			return fmt.Errorf("failed to download lfs object %s (%s): %w", pointer.OID, path, err)
+		}
+	}
+	return nil
+}
+
+// pushLFSObjects uploads any LFS objects referenced by pointer files at
+// localPath that the remote doesn't already have, mirroring them alongside
+// the regular `git push`, authenticating with auth.
+func pushLFSObjects(ctx context.Context, localPath, remoteURL, branchName string, auth transport.AuthMethod, options LFSOptions) error {
+	if !options.Enabled {
+		return nil
+	}
+
+	if options.PreferShellOut && hasGitLFSBinaries() {
+		cmd := exec.CommandContext(ctx, "git", "lfs", "push", "origin", branchName)
+		cmd.Dir = localPath
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("git lfs push failed in %s: %w: %s", localPath, err, out)
+		}
+		return nil
+	}
+
+	pointers, err := findLFSPointerFiles(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to find lfs pointer files in %s: %w", localPath, err)
+	}
+	if len(pointers) == 0 {
+		return nil
+	}
+
+	actions, err := lfsBatch(ctx, remoteURL, "upload", pointers, auth)
+	if err != nil {
+		return fmt.Errorf("failed to request lfs batch upload from %s: %w", remoteURL, err)
+	}
+	for path, pointer := range pointers {
+		action, ok := actions[pointer.OID]
+		if !ok {
+			// The server already has this object; nothing to upload.
+			continue
+		}
+		if err := uploadLFSObject(ctx, localPath, pointer, action, auth); err != nil {
+			return fmt.Errorf("failed to upload lfs object %s (%s): %w", pointer.OID, path, err)
+		}
+	}
+	return nil
+}
+
+// cleanLFSFiles rewrites every file in localPath's worktree matching a
+// "filter=lfs" pattern that currently holds real content - as opposed to an
+// already-clean pointer file - into a pointer file, stashing the original
+// bytes under .git/lfs/objects. This is git's own LFS clean filter, which
+// runs on `git add` in a real checkout; go-git's Worktree.Commit has no
+// filter support, so commitChanges has to run it explicitly before staging,
+// or pushLFSObjects would find nothing but raw committed blobs to upload.
+func cleanLFSFiles(localPath string, options LFSOptions) error {
+	if !options.Enabled {
+		return nil
+	}
+
+	patterns, err := lfsPatterns(localPath)
+	if err != nil {
+		return err
+	}
+	if len(patterns) == 0 {
+		return nil
+	}
+
+	return filepath.Walk(localPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		relPath, err := filepath.Rel(localPath, path)
+		if err != nil {
+			return err
+		}
+		if !matchesAnyPattern(relPath, patterns) {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if _, ok := parseLFSPointer(data); ok {
+			// Already a pointer file; nothing changed underneath it.
+			return nil
+		}
+		return cleanLFSFile(localPath, path, data)
+	})
+}
+
+// cleanLFSFile stashes data - path's current real content - under
+// .git/lfs/objects and overwrites path with a pointer file referencing it.
+func cleanLFSFile(localPath, path string, data []byte) error {
+	sum := sha256.Sum256(data)
+	oid := hex.EncodeToString(sum[:])
+
+	objectPath := lfsObjectPath(localPath, oid)
+	if err := os.MkdirAll(filepath.Dir(objectPath), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(objectPath, data, 0o644); err != nil {
+		return err
+	}
+
+	pointer := fmt.Sprintf("%s\noid sha256:%s\nsize %d\n", lfsPointerPreamble, oid, len(data))
+	return os.WriteFile(path, []byte(pointer), 0o644)
+}
+
+// findLFSPointerFiles walks localPath's worktree for files matching a
+// "filter=lfs" pattern in .gitattributes and parses those that are still
+// LFS pointer files (i.e. haven't already been smudged to their real
+// content).
+func findLFSPointerFiles(localPath string) (map[string]lfsPointer, error) {
+	patterns, err := lfsPatterns(localPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+
+	pointers := make(map[string]lfsPointer)
+	err = filepath.Walk(localPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		relPath, err := filepath.Rel(localPath, path)
+		if err != nil {
+			return err
+		}
+		if !matchesAnyPattern(relPath, patterns) {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		pointer, ok := parseLFSPointer(data)
+		if !ok {
+			return nil
+		}
+		pointers[relPath] = pointer
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pointers, nil
+}
+
+// lfsPatterns returns the glob patterns marked `filter=lfs` in
+// localPath/.gitattributes.
+func lfsPatterns(localPath string) ([]string, error) {
+	file, err := os.Open(filepath.Join(localPath, ".gitattributes"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		for _, attr := range fields[1:] {
+			if attr == "filter=lfs" {
+				patterns = append(patterns, fields[0])
+				break
+			}
+		}
+	}
+	return patterns, scanner.Err()
+}
+
+func matchesAnyPattern(relPath string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := filepath.Match(pattern, filepath.Base(relPath)); matched {
+			return true
+		}
+		if matched, _ := filepath.Match(pattern, relPath); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// parseLFSPointer parses the small, well-defined LFS pointer file format:
+//
+//	version https://git-lfs.github.com/spec/v1
+//	oid sha256:<hex>
+//	size <bytes>
+func parseLFSPointer(data []byte) (lfsPointer, bool) {
+	var pointer lfsPointer
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != lfsPointerPreamble {
+		return lfsPointer{}, false
+	}
+	for _, line := range lines[1:] {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "oid":
+			pointer.OID = strings.TrimPrefix(value, "sha256:")
+		case "size":
+			size, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return lfsPointer{}, false
+			}
+			pointer.Size = size
+		}
+	}
+	if pointer.OID == "" {
+		return lfsPointer{}, false
+	}
+	return pointer, true
+}
+
+// lfsBatchAction is a single "download"/"upload" action returned by the LFS
+// Batch API for an object.
+type lfsBatchAction struct {
+	Href   string            `json:"href"`
+	Header map[string]string `json:"header"`
+}
+
+// setLFSRequestAuth applies auth to an LFS HTTP request the same way it
+// would be applied to the underlying git clone/push, so Batch API and
+// object transfer requests authenticate against private hosts too. Only
+// HTTP basic auth (username/access-token) carries over; SSH auth methods
+// don't apply to the LFS Batch API, which is always plain HTTPS.
+func setLFSRequestAuth(req *http.Request, auth transport.AuthMethod) {
+	if basicAuth, ok := auth.(*gogithttp.BasicAuth); ok {
+		req.SetBasicAuth(basicAuth.Username, basicAuth.Password)
+	}
+}
+
+// lfsBatch calls the LFS Batch API (https://github.com/git-lfs/git-lfs/blob/main/docs/api/batch.md)
+// for operation ("download" or "upload") and returns the action for each
+// object OID that the server reported one for.
+func lfsBatch(ctx context.Context, remoteURL, operation string, pointers map[string]lfsPointer, auth transport.AuthMethod) (map[string]lfsBatchAction, error) {
+	objects := make([]map[string]any, 0, len(pointers))
+	for _, pointer := range pointers {
+		objects = append(objects, map[string]any{
+			"oid":  pointer.OID,
+			"size": pointer.Size,
+		})
+	}
+	body, err := json.Marshal(map[string]any{
+		"operation": operation,
+		"transfers": []string{"basic"},
+		"objects":   objects,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	batchURL := strings.TrimSuffix(remoteURL, ".git") + ".git/info/lfs/objects/batch"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, batchURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.git-lfs+json")
+	req.Header.Set("Content-Type", "application/vnd.git-lfs+json")
+	setLFSRequestAuth(req, auth)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("lfs batch request to %s returned %s", batchURL, resp.Status)
+	}
+
+	var result struct {
+		Objects []struct {
+			OID     string                    `json:"oid"`
+			Actions map[string]lfsBatchAction `json:"actions"`
+		} `json:"objects"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode lfs batch response: %w", err)
+	}
+
+	actions := make(map[string]lfsBatchAction, len(result.Objects))
+	for _, object := range result.Objects {
+		if action, ok := object.Actions[operation]; ok {
+			actions[object.OID] = action
+		}
+	}
+	return actions, nil
+}
+
+// downloadLFSObject fetches an LFS object's content via action and writes
+// it both to .git/lfs/objects and over the pointer file at relPath in the
+// worktree, mirroring what `git lfs smudge` does on checkout - without this
+// second write, callers reading relPath after clone would still see pointer
+// text instead of the real asset.
+func downloadLFSObject(ctx context.Context, localPath, relPath string, pointer lfsPointer, action lfsBatchAction, auth transport.AuthMethod) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, action.Href, nil)
+	if err != nil {
+		return err
+	}
+	for key, value := range action.Header {
+		req.Header.Set(key, value)
+	}
+	setLFSRequestAuth(req, auth)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download returned %s", resp.Status)
+	}
+
+	objectPath := lfsObjectPath(localPath, pointer.OID)
+	if err := os.MkdirAll(filepath.Dir(objectPath), 0o755); err != nil {
+		return err
+	}
+	objectFile, err := os.Create(objectPath)
+	if err != nil {
+		return err
+	}
+	defer objectFile.Close()
+
+	worktreePath := filepath.Join(localPath, relPath)
+	worktreeFile, err := os.Create(worktreePath)
+	if err != nil {
+		return err
+	}
+	defer worktreeFile.Close()
+
+	if _, err := io.Copy(io.MultiWriter(objectFile, worktreeFile), resp.Body); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// uploadLFSObject uploads an LFS object's content from .git/lfs/objects to
+// the server via action.
+func uploadLFSObject(ctx context.Context, localPath string, pointer lfsPointer, action lfsBatchAction, auth transport.AuthMethod) error {
+	objectFile, err := os.Open(lfsObjectPath(localPath, pointer.OID))
+	if err != nil {
+		return err
+	}
+	defer objectFile.Close()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, action.Href, objectFile)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = pointer.Size
+	for key, value := range action.Header {
+		req.Header.Set(key, value)
+	}
+	setLFSRequestAuth(req, auth)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("upload returned %s", resp.Status)
+	}
+	return nil
+}
+
+// lfsObjectPath returns the path LFS objects are stored at under a
+// worktree's .git directory, following the upstream git-lfs on-disk layout:
+// .git/lfs/objects/<oid[0:2]>/<oid[2:4]>/<oid>.
+func lfsObjectPath(localPath, oid string) string {
+	return filepath.Join(localPath, ".git", "lfs", "objects", oid[0:2], oid[2:4], oid)
+}
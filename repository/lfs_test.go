@@ -0,0 +1,53 @@
+package repository
+
+import "testing"
+
+func TestParseLFSPointer(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		want   lfsPointer
+		wantOK bool
+	}{
+		{
+			name: "valid pointer",
+			data: "version https://git-lfs.github.com/spec/v1\n" +
+				"oid sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393\n" +
+				"size 12345\n",
+			want:   lfsPointer{OID: "4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393", Size: 12345},
+			wantOK: true,
+		},
+		{
+			name:   "not a pointer file",
+			data:   "#!/usr/bin/env bash\necho hello\n",
+			wantOK: false,
+		},
+		{
+			name:   "wrong preamble version",
+			data:   "version https://git-lfs.github.com/spec/v2\noid sha256:abc\nsize 1\n",
+			wantOK: false,
+		},
+		{
+			name:   "missing oid",
+			data:   "version https://git-lfs.github.com/spec/v1\nsize 1\n",
+			wantOK: false,
+		},
+		{
+			name:   "non-numeric size",
+			data:   "version https://git-lfs.github.com/spec/v1\noid sha256:abc\nsize not-a-number\n",
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseLFSPointer([]byte(tt.data))
+			if ok != tt.wantOK {
+				t.Fatalf("parseLFSPointer() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && got != tt.want {
+				t.Fatalf("parseLFSPointer() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package repository
+
+import "time"
+
+// GitHubOptions configures how Octopilot authenticates against GitHub when
+// cloning and pushing repositories.
+type GitHubOptions struct {
+	// Token is a GitHub personal access token or GitHub App installation
+	// token used for HTTP Basic Auth against the remote.
+	Token string
+
+	// LFS configures Git LFS handling during clone and push.
+	LFS LFSOptions
+
+	// FetchAllRefs, when true, fetches all remote branches into
+	// refs/remotes/origin/* right after cloning, so update strategies can
+	// read state from branches other than the one checked out (see
+	// GitRepository.WithBranch).
+	FetchAllRefs bool
+
+	// Retry configures retry behaviour for clone and push against this
+	// host. A zero value means try once, with no retries.
+	Retry RetryOptions
+
+	// CacheDir, when set, makes cloneGitRepository reuse a bare mirror of
+	// each repository under CacheDir/<owner>/<name>.git across runs,
+	// incrementally fetching it instead of doing a full clone every time.
+	CacheDir string
+}
+
+// RetryOptions configures retrying transient clone/push failures with
+// exponential backoff.
+type RetryOptions struct {
+	// MaxAttempts is the total number of attempts, including the first.
+	// Zero or one means no retries.
+	MaxAttempts int
+
+	// InitialBackoff is the base wait before the second attempt, to which a
+	// random jitter of up to the same amount again is added; the base
+	// doubles on each subsequent attempt. Defaults to 1s if unset.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the exponential backoff wait, jitter included. Waits
+	// requested by the host's rate-limit headers are not capped. Defaults to
+	// 30s if unset.
+	MaxBackoff time.Duration
+}
+
+// LFSOptions configures how Git LFS pointer files are resolved to their
+// actual content on clone, and how new/changed LFS objects are uploaded on
+// push.
+type LFSOptions struct {
+	// Enabled turns on LFS handling. When false (the default), LFS pointer
+	// files are left untouched, matching Octopilot's previous behaviour.
+	Enabled bool
+
+	// PreferShellOut, when true, runs `git lfs pull` after clone and
+	// `git lfs push` before push instead of using the built-in pure-Go
+	// LFS client; the clone and push themselves still go through go-git. Ignored if either binary isn't found on PATH, in which
+	// case the pure-Go client is used regardless.
+	PreferShellOut bool
+}
+
+// UpdateOptions configures how changes are committed to a repository once an
+// update strategy has modified its local checkout.
+type UpdateOptions struct {
+	Git GitOptions
+
+	// LFS configures Git LFS handling at commit time: files matching a
+	// "filter=lfs" pattern are converted from real content back into
+	// pointer files before they're staged, mirroring git's own clean
+	// filter. Normally the same LFSOptions passed to cloneGitRepository.
+	LFS LFSOptions
+}
+
+// GitOptions configures the commit(s) created for a given update.
+type GitOptions struct {
+	StagePatterns   []string
+	StageAllChanged bool
+
+	CommitTitle  string
+	CommitBody   string
+	CommitFooter string
+
+	AuthorName  string
+	AuthorEmail string
+
+	CommitterName  string
+	CommitterEmail string
+
+	// SigningMode turns on commit signing and selects the signature format:
+	// "gpg" or "ssh". Any other value, including empty, leaves commits
+	// unsigned. The other Signing* fields are interpreted according to
+	// that mode.
+	SigningMode string
+
+	// SigningKeyPath points at a private key file on disk: an armored GPG
+	// private key for "gpg" mode, or an OpenSSH private key for "ssh" mode.
+	// SigningKeyArmored carries the same key inline instead of on disk, and
+	// takes precedence over SigningKeyPath when set.
+	SigningKeyPath    string
+	SigningKeyArmored string
+
+	// SigningKeyPassphrase decrypts SigningKeyPath/SigningKeyArmored when
+	// the key is passphrase-protected.
+	SigningKeyPassphrase string
+
+	// SigningIdentity optionally selects which identity (GPG user ID) on a
+	// multi-identity keyring should be used to sign, independently of the
+	// author/committer names configured above.
+	SigningIdentity string
+}
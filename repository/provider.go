@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	gogithttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gogitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+)
+
+// AuthOption selects how a GitProvider authenticates Git operations
+// (distinct from how it authenticates API calls for PR/MR creation).
+type AuthOption string
+
+const (
+	AuthOptionSSH              AuthOption = "ssh"
+	AuthOptionSSHAgent         AuthOption = "ssh_agent"
+	AuthOptionUsernamePassword AuthOption = "username_password"
+	AuthOptionAccessToken      AuthOption = "access_token"
+	AuthOptionAnonymous        AuthOption = "anonymous"
+)
+
+// GitConfig carries everything a GitProvider needs to build a clone/push URL
+// and an auth transport for a single repository. It is resolved per
+// repository so that a single Octopilot run can target multiple
+// heterogeneous Git hosts at once.
+type GitConfig struct {
+	Provider string
+	Host     string
+
+	Auth AuthOption
+
+	Username    string
+	Password    string
+	AccessToken string
+
+	SSHUser          string
+	SSHKeyPath       string
+	SSHKeyPassphrase string
+}
+
+// GitProvider abstracts the Git-host-specific pieces of cloning, pushing and
+// opening pull/merge requests, so that hosts other than the originally
+// hardcoded github.worldpay.com GitHub Enterprise instance can be supported.
+type GitProvider interface {
+	// Name identifies the provider, e.g. "github", "gitlab", "gitea".
+	Name() string
+
+	// CloneURL returns the URL used to clone/push repo, given config (for
+	// its Host override).
+	CloneURL(repo Repository, config GitConfig) string
+
+	// Auth returns the go-git transport auth method to use for repo, based
+	// on config.
+	Auth(config GitConfig) (transport.AuthMethod, error)
+
+	// CreatePullRequest opens a pull/merge request for the given branch and
+	// returns its URL.
+	CreatePullRequest(ctx context.Context, repo Repository, config GitConfig, opts PullRequestOptions) (string, error)
+}
+
+// PullRequestOptions describes the pull/merge request to open once a branch
+// has been pushed.
+type PullRequestOptions struct {
+	Title      string
+	Body       string
+	FromBranch string
+	ToBranch   string
+}
+
+// NewGitProvider builds the GitProvider for the given provider name
+// ("github", "github-enterprise", "gitlab" or "gitea"). An empty name
+// defaults to "github-enterprise", matching Octopilot's original
+// github.worldpay.com-only behaviour.
+func NewGitProvider(name string) (GitProvider, error) {
+	switch name {
+	case "", "github-enterprise":
+		return &githubProvider{enterpriseHost: "github.worldpay.com"}, nil
+	case "github":
+		return &githubProvider{}, nil
+	case "gitlab":
+		return &gitlabProvider{}, nil
+	case "gitea":
+		return &giteaProvider{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported git provider %q", name)
+	}
+}
+
+// gitConfigForRepository resolves a GitConfig from a repository's Params,
+// following the same pattern as the pre-existing "branch" param. Missing
+// values fall back to the GitHubOptions-based defaults used before
+// per-repository providers were supported.
+func gitConfigForRepository(repo Repository, options GitHubOptions) GitConfig {
+	config := GitConfig{
+		Provider:         repo.Params["git-provider"],
+		Host:             repo.Params["git-host"],
+		Auth:             AuthOption(repo.Params["git-auth"]),
+		Username:         repo.Params["git-username"],
+		Password:         repo.Params["git-password"],
+		AccessToken:      repo.Params["git-access-token"],
+		SSHUser:          repo.Params["git-ssh-user"],
+		SSHKeyPath:       repo.Params["git-ssh-key-path"],
+		SSHKeyPassphrase: repo.Params["git-ssh-key-passphrase"],
+	}
+	if config.Auth == "" {
+		config.Auth = AuthOptionAccessToken
+	}
+	if config.AccessToken == "" {
+		config.AccessToken = options.Token
+	}
+	return config
+}
+
+// sshAuthMethod builds the go-git SSH transport auth for config, using an
+// explicit private key file or falling back to the local ssh-agent.
+func sshAuthMethod(config GitConfig) (transport.AuthMethod, error) {
+	user := config.SSHUser
+	if user == "" {
+		user = "git"
+	}
+	if config.Auth == AuthOptionSSHAgent || config.SSHKeyPath == "" {
+		auth, err := gogitssh.NewSSHAgentAuth(user)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build ssh agent auth: %w", err)
+		}
+		return auth, nil
+	}
+	auth, err := gogitssh.NewPublicKeysFromFile(user, config.SSHKeyPath, config.SSHKeyPassphrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ssh key %s: %w", config.SSHKeyPath, err)
+	}
+	return auth, nil
+}
+
+// basicAuthMethod builds the go-git HTTP basic-auth transport for config.
+func basicAuthMethod(username string, config GitConfig) transport.AuthMethod {
+	password := config.Password
+	if config.Auth == AuthOptionAccessToken {
+		password = config.AccessToken
+	}
+	return &gogithttp.BasicAuth{
+		Username: username,
+		Password: password,
+	}
+}
+
+// authMethodFor dispatches to the right auth builder for config.Auth,
+// shared across providers that only differ in default HTTP basic-auth
+// username.
+func authMethodFor(config GitConfig, defaultUsername string) (transport.AuthMethod, error) {
+	switch config.Auth {
+	case AuthOptionAnonymous:
+		return nil, nil
+	case AuthOptionSSH, AuthOptionSSHAgent:
+		return sshAuthMethod(config)
+	case AuthOptionUsernamePassword:
+		username := config.Username
+		if username == "" {
+			username = defaultUsername
+		}
+		return basicAuthMethod(username, config), nil
+	case AuthOptionAccessToken, "":
+		return basicAuthMethod(defaultUsername, config), nil
+	default:
+		return nil, fmt.Errorf("unsupported git auth option %q", config.Auth)
+	}
+}
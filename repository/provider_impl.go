@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/google/go-github/v53/github"
+)
+
+// githubProvider talks to GitHub.com, or to a GitHub Enterprise instance
+// when enterpriseHost is set (this is what backs the original hardcoded
+// github.worldpay.com behaviour).
+type githubProvider struct {
+	enterpriseHost string
+}
+
+func (p *githubProvider) Name() string {
+	if p.enterpriseHost != "" {
+		return "github-enterprise"
+	}
+	return "github"
+}
+
+func (p *githubProvider) host(config GitConfig) string {
+	if config.Host != "" {
+		return config.Host
+	}
+	if p.enterpriseHost != "" {
+		return p.enterpriseHost
+	}
+	return "github.com"
+}
+
+func (p *githubProvider) CloneURL(repo Repository, config GitConfig) string {
+	return fmt.Sprintf("https://%s/%s.git", p.host(config), repo.FullName())
+}
+
+func (p *githubProvider) Auth(config GitConfig) (transport.AuthMethod, error) {
+	return authMethodFor(config, "x-access-token")
+}
+
+func (p *githubProvider) CreatePullRequest(ctx context.Context, repo Repository, config GitConfig, opts PullRequestOptions) (string, error) {
+	client, err := githubClient(ctx, p.host(config), config.AccessToken, p.enterpriseHost != "" || config.Host != "")
+	if err != nil {
+		return "", err
+	}
+
+	pr, _, err := client.PullRequests.Create(ctx, repo.Owner, repo.Name, &github.NewPullRequest{
+		Title: &opts.Title,
+		Body:  &opts.Body,
+		Head:  &opts.FromBranch,
+		Base:  &opts.ToBranch,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create pull request on %s: %w", repo.FullName(), err)
+	}
+	return pr.GetHTMLURL(), nil
+}
+
+// gitlabProvider talks to GitLab.com or a self-hosted GitLab instance.
+type gitlabProvider struct{}
+
+func (p *gitlabProvider) Name() string { return "gitlab" }
+
+func (p *gitlabProvider) host(config GitConfig) string {
+	if config.Host != "" {
+		return config.Host
+	}
+	return "gitlab.com"
+}
+
+func (p *gitlabProvider) CloneURL(repo Repository, config GitConfig) string {
+	return fmt.Sprintf("https://%s/%s.git", p.host(config), repo.FullName())
+}
+
+func (p *gitlabProvider) Auth(config GitConfig) (transport.AuthMethod, error) {
+	return authMethodFor(config, "oauth2")
+}
+
+func (p *gitlabProvider) CreatePullRequest(ctx context.Context, repo Repository, config GitConfig, opts PullRequestOptions) (string, error) {
+	projectPath := url.PathEscape(repo.FullName())
+	apiURL := fmt.Sprintf("https://%s/api/v4/projects/%s/merge_requests", p.host(config), projectPath)
+
+	body, err := json.Marshal(map[string]string{
+		"source_branch": opts.FromBranch,
+		"target_branch": opts.ToBranch,
+		"title":         opts.Title,
+		"description":   opts.Body,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("PRIVATE-TOKEN", config.AccessToken)
+
+	var result struct {
+		WebURL string `json:"web_url"`
+	}
+	if err := doJSONRequest(req, &result); err != nil {
+		return "", fmt.Errorf("failed to create merge request on %s: %w", repo.FullName(), err)
+	}
+	return result.WebURL, nil
+}
+
+// giteaProvider talks to a Gitea (or Forgejo) instance.
+type giteaProvider struct{}
+
+func (p *giteaProvider) Name() string { return "gitea" }
+
+func (p *giteaProvider) host(config GitConfig) string {
+	if config.Host != "" {
+		return config.Host
+	}
+	return "gitea.com"
+}
+
+func (p *giteaProvider) CloneURL(repo Repository, config GitConfig) string {
+	return fmt.Sprintf("https://%s/%s.git", p.host(config), repo.FullName())
+}
+
+func (p *giteaProvider) Auth(config GitConfig) (transport.AuthMethod, error) {
+	return authMethodFor(config, "x-access-token")
+}
+
+func (p *giteaProvider) CreatePullRequest(ctx context.Context, repo Repository, config GitConfig, opts PullRequestOptions) (string, error) {
+	apiURL := fmt.Sprintf("https://%s/api/v1/repos/%s/%s/pulls", p.host(config), repo.Owner, repo.Name)
+
+	body, err := json.Marshal(map[string]string{
+		"head":  opts.FromBranch,
+		"base":  opts.ToBranch,
+		"title": opts.Title,
+		"body":  opts.Body,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "token "+config.AccessToken)
+
+	var result struct {
+		HTMLURL string `json:"html_url"`
+	}
+	if err := doJSONRequest(req, &result); err != nil {
+		return "", fmt.Errorf("failed to create pull request on %s: %w", repo.FullName(), err)
+	}
+	return result.HTMLURL, nil
+}
+
+// doJSONRequest sends req and decodes a successful JSON response into out.
+func doJSONRequest(req *http.Request, out any) error {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("request to %s returned %s: %s", req.URL, resp.Status, respBody)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,17 @@
+package repository
+
+import "fmt"
+
+// Repository represents a single Git repository to update, identified by its
+// owner and name, plus free-form parameters used by update strategies.
+type Repository struct {
+	Owner  string
+	Name   string
+	Params map[string]string
+}
+
+// FullName returns the repository's "owner/name" identifier, as used in
+// clone URLs, host API paths and log and error messages.
+func (r Repository) FullName() string {
+	return fmt.Sprintf("%s/%s", r.Owner, r.Name)
+}
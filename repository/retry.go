@@ -0,0 +1,255 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	transportclient "github.com/go-git/go-git/v5/plumbing/transport/client"
+	gogithttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/sirupsen/logrus"
+)
+
+// retryDecision is what classifyRetry concludes about a failed attempt:
+// whether to retry at all, and how long to wait before doing so (zero means
+// "use the normal exponential backoff").
+type retryDecision struct {
+	retry bool
+	wait  time.Duration
+}
+
+// withRetry runs op up to options.MaxAttempts times (at least once),
+// backing off exponentially with jitter between attempts, unless
+// classifyRetry says a given error isn't worth retrying.
+func withRetry(ctx context.Context, options RetryOptions, op func(attempt int) error) error {
+	maxAttempts := options.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	backoff := options.InitialBackoff
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+	maxBackoff := options.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = 30 * time.Second
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		lastErr = op(attempt)
+		if lastErr == nil {
+			return nil
+		}
+
+		decision := classifyRetry(lastErr)
+		if !decision.retry || attempt == maxAttempts {
+			return lastErr
+		}
+
+		wait := decision.wait
+		if wait <= 0 {
+			wait = backoff + time.Duration(rand.Int63n(int64(backoff)+1))
+			if wait > maxBackoff {
+				wait = maxBackoff
+			}
+			backoff *= 2
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+		logrus.WithError(lastErr).WithFields(logrus.Fields{
+			"attempt": attempt,
+			"wait":    wait.String(),
+		}).Debug("Retrying git operation")
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+	return lastErr
+}
+
+// permanentTransportErrors are go-git errors that mean the operation will
+// never succeed no matter how many times it's retried: the repo doesn't
+// exist, or the credentials it was given are rejected outright. Retrying
+// these just burns the full backoff schedule against every repo in a batch
+// for no benefit.
+var permanentTransportErrors = []error{
+	transport.ErrRepositoryNotFound,
+	transport.ErrAuthenticationRequired,
+	transport.ErrAuthorizationFailed,
+	transport.ErrInvalidAuthMethod,
+}
+
+// permanentRefSpecErrors are malformed-refspec errors from the config
+// package: a bug in how octopilot built the refspec, not a transient host
+// failure, so retrying can't fix them either.
+var permanentRefSpecErrors = []error{
+	config.ErrRefSpecMalformedSeparator,
+	config.ErrRefSpecMalformedWildcard,
+}
+
+// classifyRetry decides whether err is worth retrying, and how long to wait
+// first if the host told us via rate-limit headers.
+func classifyRetry(err error) retryDecision {
+	for _, permanentErr := range permanentTransportErrors {
+		if errors.Is(err, permanentErr) {
+			return retryDecision{retry: false}
+		}
+	}
+	for _, permanentErr := range permanentRefSpecErrors {
+		if errors.Is(err, permanentErr) {
+			return retryDecision{retry: false}
+		}
+	}
+
+	var rlErr *rateLimitError
+	if errors.As(err, &rlErr) {
+		return retryDecision{retry: true, wait: rlErr.retryAfter}
+	}
+
+	return retryDecision{retry: true}
+}
+
+// rateLimitError is synthesized by rateLimitTransport when a Git host
+// responds 403/429 with rate-limit headers, so withRetry can honor the
+// host's requested wait instead of guessing via plain backoff.
+type rateLimitError struct {
+	status     string
+	retryAfter time.Duration
+}
+
+func (e *rateLimitError) Error() string {
+	return fmt.Sprintf("rate limited by git host (%s), retry after %s", e.status, e.retryAfter)
+}
+
+// rateLimitTransport wraps an http.RoundTripper and turns 403/429 responses
+// carrying Retry-After or X-RateLimit-Reset headers into a *rateLimitError,
+// so it survives as a typed error through go-git's HTTP transport.
+type rateLimitTransport struct {
+	base http.RoundTripper
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp == nil {
+		return resp, err
+	}
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return resp, nil
+	}
+	if wait, ok := rateLimitWait(resp.Header); ok {
+		// http.RoundTripper implementations must not return both a
+		// response and an error - the caller isn't allowed to look at
+		// resp in that case, so nothing would ever close its body.
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return nil, &rateLimitError{status: resp.Status, retryAfter: wait}
+	}
+	return resp, nil
+}
+
+// rateLimitWait parses how long to wait from either the standard
+// Retry-After header or GitHub's X-RateLimit-Reset header.
+func rateLimitWait(header http.Header) (time.Duration, bool) {
+	if v := header.Get("Retry-After"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil {
+			return time.Duration(secs) * time.Second, true
+		}
+	}
+	if v := header.Get("X-RateLimit-Reset"); v != "" {
+		if unix, err := strconv.ParseInt(v, 10, 64); err == nil {
+			if wait := time.Until(time.Unix(unix, 0)); wait > 0 {
+				return wait, true
+			}
+		}
+	}
+	return 0, false
+}
+
+var installRateLimitTransportOnce sync.Once
+
+// installRateLimitTransport registers rateLimitTransport as go-git's HTTPS
+// client, once per process, so cloneGitRepository and pushChanges can
+// distinguish rate-limit responses from other failures.
+func installRateLimitTransport() {
+	installRateLimitTransportOnce.Do(func() {
+		transportclient.InstallProtocol("https", gogithttp.NewClient(&http.Client{
+			Transport: &rateLimitTransport{base: http.DefaultTransport},
+		}))
+	})
+}
+
+func init() {
+	installRateLimitTransport()
+}
+
+// fetchAndRebase pulls the latest origin/branchName and re-parents the
+// local branch's tip commit onto it. Octopilot pushes a single automated
+// commit per update, so this only needs to move that one commit rather
+// than replay a whole series - go-git has no built-in rebase for the
+// general case.
+func fetchAndRebase(ctx context.Context, gitRepo *git.Repository, branchName string, auth transport.AuthMethod) error {
+	if err := gitRepo.FetchContext(ctx, &git.FetchOptions{
+		RefSpecs: []config.RefSpec{
+			config.RefSpec(fmt.Sprintf("+refs/heads/%[1]s:refs/remotes/origin/%[1]s", branchName)),
+		},
+		Auth: auth,
+	}); err != nil && err != git.NoErrAlreadyUpToDate {
+		return fmt.Errorf("failed to fetch %s before rebasing: %w", branchName, err)
+	}
+
+	localRefName := plumbing.NewBranchReferenceName(branchName)
+	localRef, err := gitRepo.Reference(localRefName, true)
+	if err != nil {
+		return fmt.Errorf("failed to read local branch %s: %w", branchName, err)
+	}
+	remoteRef, err := gitRepo.Reference(plumbing.NewRemoteReferenceName("origin", branchName), true)
+	if err != nil {
+		return fmt.Errorf("failed to read updated origin/%s: %w", branchName, err)
+	}
+	if localRef.Hash() == remoteRef.Hash() {
+		return nil
+	}
+
+	localCommit, err := gitRepo.CommitObject(localRef.Hash())
+	if err != nil {
+		return fmt.Errorf("failed to load local commit for %s: %w", branchName, err)
+	}
+
+	rebased := &object.Commit{
+		Author:       localCommit.Author,
+		Committer:    localCommit.Committer,
+		Message:      localCommit.Message,
+		TreeHash:     localCommit.TreeHash,
+		ParentHashes: []plumbing.Hash{remoteRef.Hash()},
+	}
+	obj := gitRepo.Storer.NewEncodedObject()
+	obj.SetType(plumbing.CommitObject)
+	if err := rebased.Encode(obj); err != nil {
+		return fmt.Errorf("failed to encode rebased commit: %w", err)
+	}
+	newHash, err := gitRepo.Storer.SetEncodedObject(obj)
+	if err != nil {
+		return fmt.Errorf("failed to store rebased commit: %w", err)
+	}
+
+	if err := gitRepo.Storer.SetReference(plumbing.NewHashReference(localRefName, newHash)); err != nil {
+		return fmt.Errorf("failed to update local branch %s to rebased commit: %w", branchName, err)
+	}
+	return nil
+}
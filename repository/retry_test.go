@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+func TestClassifyRetryPermanentErrors(t *testing.T) {
+	permanent := []error{
+		transport.ErrRepositoryNotFound,
+		transport.ErrAuthenticationRequired,
+		transport.ErrAuthorizationFailed,
+		transport.ErrInvalidAuthMethod,
+		config.ErrRefSpecMalformedSeparator,
+		config.ErrRefSpecMalformedWildcard,
+	}
+	for _, permanentErr := range permanent {
+		t.Run(permanentErr.Error(), func(t *testing.T) {
+			wrapped := fmt.Errorf("push failed: %w", permanentErr)
+			decision := classifyRetry(wrapped)
+			if decision.retry {
+				t.Fatalf("classifyRetry(%v) = retry true, want false", wrapped)
+			}
+		})
+	}
+}
+
+func TestClassifyRetryTransientErrors(t *testing.T) {
+	decision := classifyRetry(errors.New("connection reset by peer"))
+	if !decision.retry {
+		t.Fatal("classifyRetry() of an unrecognized error = retry false, want true")
+	}
+	if decision.wait != 0 {
+		t.Fatalf("classifyRetry() wait = %v, want 0 (plain backoff)", decision.wait)
+	}
+}
+
+func TestClassifyRetryRateLimit(t *testing.T) {
+	rlErr := &rateLimitError{status: "403 Forbidden", retryAfter: 42 * time.Second}
+	decision := classifyRetry(rlErr)
+	if !decision.retry {
+		t.Fatal("classifyRetry() of a rate limit error = retry false, want true")
+	}
+	if decision.wait != 42*time.Second {
+		t.Fatalf("classifyRetry() wait = %v, want 42s", decision.wait)
+	}
+}
@@ -0,0 +1,228 @@
+package repository
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"golang.org/x/crypto/ssh"
+)
+
+// loadGPGSignKey reads the GPG private key configured in options (inline
+// armored key taking precedence over a key file), decrypts it if needed, and
+// returns the entity to use as git.CommitOptions.SignKey.
+func loadGPGSignKey(options GitOptions) (*openpgp.Entity, error) {
+	armored := options.SigningKeyArmored
+	if armored == "" {
+		data, err := os.ReadFile(options.SigningKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read gpg signing key %s: %w", options.SigningKeyPath, err)
+		}
+		armored = string(data)
+	}
+
+	entityList, err := openpgp.ReadArmoredKeyRing(strings.NewReader(armored))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gpg signing key ring: %w", err)
+	}
+
+	entity, err := selectGPGEntity(entityList, options.SigningIdentity)
+	if err != nil {
+		return nil, err
+	}
+
+	if entity.PrivateKey != nil && entity.PrivateKey.Encrypted {
+		if err := entity.PrivateKey.Decrypt([]byte(options.SigningKeyPassphrase)); err != nil {
+			return nil, fmt.Errorf("failed to decrypt gpg signing key: %w", err)
+		}
+	}
+	for _, subKey := range entity.Subkeys {
+		if subKey.PrivateKey != nil && subKey.PrivateKey.Encrypted {
+			if err := subKey.PrivateKey.Decrypt([]byte(options.SigningKeyPassphrase)); err != nil {
+				return nil, fmt.Errorf("failed to decrypt gpg signing subkey: %w", err)
+			}
+		}
+	}
+
+	return entity, nil
+}
+
+// selectGPGEntity picks the entity matching identity (a GPG user ID, e.g.
+// "Jane Doe <jane@example.com>") out of a keyring, or the first entity if
+// identity is empty.
+func selectGPGEntity(entityList openpgp.EntityList, identity string) (*openpgp.Entity, error) {
+	if len(entityList) == 0 {
+		return nil, fmt.Errorf("no entity found in gpg signing key ring")
+	}
+	if identity == "" {
+		return entityList[0], nil
+	}
+	for _, entity := range entityList {
+		for name := range entity.Identities {
+			if name == identity {
+				return entity, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no entity matching signing identity %q found in gpg signing key ring", identity)
+}
+
+// signCommitWithSSH re-signs the commit at commitHash using an SSH signature,
+// storing the signed commit as a new object and returning its hash. go-git's
+// Worktree.Commit has no SSH signing support, so the commit is first created
+// unsigned and then rewritten here with a "gpgsig" header carrying the SSH
+// signature, the same header git uses for GPG signatures.
+func signCommitWithSSH(gitRepo *git.Repository, commitHash plumbing.Hash, options GitOptions) (plumbing.Hash, error) {
+	commit, err := gitRepo.CommitObject(commitHash)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to load commit %s to sign: %w", commitHash, err)
+	}
+
+	payload, err := encodeCommit(commit)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to serialize commit %s for signing: %w", commitHash, err)
+	}
+
+	signature, err := signPayloadWithSSH(payload, options)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	commit.PGPSignature = signature
+	newObj := gitRepo.Storer.NewEncodedObject()
+	newObj.SetType(plumbing.CommitObject)
+	if err := commit.Encode(newObj); err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to serialize signed commit: %w", err)
+	}
+	newHash, err := gitRepo.Storer.SetEncodedObject(newObj)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to store signed commit: %w", err)
+	}
+
+	return newHash, nil
+}
+
+// encodeCommit returns the canonical serialized form of commit, i.e. the
+// bytes an SSH or GPG signature is computed over.
+func encodeCommit(commit *object.Commit) ([]byte, error) {
+	obj := &plumbing.MemoryObject{}
+	if err := commit.Encode(obj); err != nil {
+		return nil, err
+	}
+	reader, err := obj.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// signPayloadWithSSH signs payload with the SSH key configured in options
+// and returns an armored "SSH SIGNATURE" block suitable for a commit's
+// gpgsig header, in the format produced by `ssh-keygen -Y sign -n git`.
+func signPayloadWithSSH(payload []byte, options GitOptions) (string, error) {
+	keyData := []byte(options.SigningKeyArmored)
+	if len(keyData) == 0 {
+		var err error
+		keyData, err = os.ReadFile(options.SigningKeyPath)
+		if err != nil {
+			return "", fmt.Errorf("failed to read ssh signing key %s: %w", options.SigningKeyPath, err)
+		}
+	}
+
+	var (
+		signer ssh.Signer
+		err    error
+	)
+	if options.SigningKeyPassphrase != "" {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(keyData, []byte(options.SigningKeyPassphrase))
+	} else {
+		signer, err = ssh.ParsePrivateKey(keyData)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to parse ssh signing key: %w", err)
+	}
+
+	preimage := sshsigPreimage(sshsigNamespace, payload)
+	sig, err := signer.Sign(rand.Reader, preimage)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign commit with ssh key: %w", err)
+	}
+
+	return armorSSHSignature(signer.PublicKey(), sig), nil
+}
+
+// sshsigMagicPreamble is the fixed 6-byte literal that opens both the
+// SSHSIG signed pre-image and the on-disk signature blob. It is NOT an SSH
+// wire string (i.e. not length-prefixed) - see PROTOCOL.sshsig in the
+// OpenSSH source tree.
+const sshsigMagicPreamble = "SSHSIG"
+
+const (
+	sshsigNamespace = "git"
+	sshsigHashAlg   = "sha512"
+)
+
+// sshsigPreimage builds the exact byte sequence an SSH key signs to produce
+// a valid SSHSIG signature over message, per PROTOCOL.sshsig:
+//
+//	"SSHSIG" || string(namespace) || string(reserved) || string(hash_algorithm) || string(H(message))
+func sshsigPreimage(namespace string, message []byte) []byte {
+	digest := sha512.Sum512(message)
+	fields := ssh.Marshal(struct {
+		Namespace string
+		Reserved  string
+		HashAlg   string
+		Hash      []byte
+	}{
+		Namespace: namespace,
+		HashAlg:   sshsigHashAlg,
+		Hash:      digest[:],
+	})
+	return append([]byte(sshsigMagicPreamble), fields...)
+}
+
+// armorSSHSignature wraps a raw SSH signature and its public key in the
+// ASCII-armored "SSH SIGNATURE" block that `ssh-keygen -Y sign`/git emit:
+// the literal "SSHSIG" magic followed by the SSH-wire-encoded envelope
+// (version, public key, namespace, reserved, hash algorithm, signature).
+func armorSSHSignature(pub ssh.PublicKey, sig *ssh.Signature) string {
+	envelope := ssh.Marshal(struct {
+		Version   uint32
+		PublicKey []byte
+		Namespace string
+		Reserved  string
+		HashAlg   string
+		Signature []byte
+	}{
+		Version:   1,
+		PublicKey: pub.Marshal(),
+		Namespace: sshsigNamespace,
+		HashAlg:   sshsigHashAlg,
+		Signature: ssh.Marshal(sig),
+	})
+	blob := append([]byte(sshsigMagicPreamble), envelope...)
+
+	encoded := base64.StdEncoding.EncodeToString(blob)
+
+	var buf bytes.Buffer
+	buf.WriteString("-----BEGIN SSH SIGNATURE-----\n")
+	for len(encoded) > 76 {
+		buf.WriteString(encoded[:76])
+		buf.WriteByte('\n')
+		encoded = encoded[76:]
+	}
+	buf.WriteString(encoded)
+	buf.WriteByte('\n')
+	buf.WriteString("-----END SSH SIGNATURE-----\n")
+	return buf.String()
+}